@@ -19,7 +19,9 @@ package eth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -38,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -48,6 +51,26 @@ type EthAPIBackendV2 struct {
 	allowUnprotectedTxs bool
 	eth                 *Ethereum
 	gpo                 *gasprice.Oracle
+
+	// plugins holds the external modules registered through
+	// RegisterBackendPlugin. It is created lazily, guarded by pluginsOnce,
+	// so every access must go through the pluginsRegistry accessor rather
+	// than reading the field directly. See backend_plugins.go.
+	pluginsOnce    sync.Once
+	plugins        *pluginRegistry
+	pluginsStarted bool
+
+	// blobLimboCache retains sidecars of blob transactions removed from the
+	// pool by a reorg. See backend_bloblimbo.go.
+	blobLimboOnce  sync.Once
+	blobLimboCache *blobLimbo
+
+	// stack and httpHandlers back RegisterHTTPHandler. httpHandlers is
+	// created lazily, guarded by httpHandlersOnce; access it only through
+	// the httpHandlersRegistry accessor. See backend_http.go.
+	stack            *node.Node
+	httpHandlersOnce sync.Once
+	httpHandlers     *httpHandlerRegistry
 }
 
 // ChainConfig returns the active chain configuration.
@@ -186,6 +209,11 @@ func (b *EthAPIBackendV2) Pending() (*types.Block, types.Receipts, vm.StateDB) {
 	return b.eth.miner.Pending()
 }
 
+// StateAndHeaderByNumber resolves number to a header and its state. Unlike
+// StateAtBlock, the state returned here is never re-executed from an
+// ancestor and so carries no refcount on the underlying trie/snapshot layer;
+// callers needing a release function for historical/traced state should use
+// StateAtBlock instead.
 func (b *EthAPIBackendV2) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (vm.StateDB, *types.Header, error) {
 	// Pending state is only known by the miner
 	if number == rpc.PendingBlockNumber {
@@ -203,13 +231,16 @@ func (b *EthAPIBackendV2) StateAndHeaderByNumber(ctx context.Context, number rpc
 	if header == nil {
 		return nil, nil, errors.New("header not found")
 	}
-	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	stateDb, err := b.eth.blockchain.StateAt(header.Root)
 	if err != nil {
 		return nil, nil, err
 	}
+	stateDb, header = b.runStateAndHeaderHooks(ctx, stateDb, header)
 	return stateDb, header, nil
 }
 
+// StateAndHeaderByNumberOrHash is StateAndHeaderByNumber, additionally
+// accepting a block hash.
 func (b *EthAPIBackendV2) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (vm.StateDB, *types.Header, error) {
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.StateAndHeaderByNumber(ctx, blockNr)
@@ -225,21 +256,145 @@ func (b *EthAPIBackendV2) StateAndHeaderByNumberOrHash(ctx context.Context, bloc
 		if blockNrOrHash.RequireCanonical && b.eth.blockchain.GetCanonicalHash(header.Number.Uint64()) != hash {
 			return nil, nil, errors.New("hash is not currently canonical")
 		}
-		stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+		stateDb, err := b.eth.blockchain.StateAt(header.Root)
 		if err != nil {
 			return nil, nil, err
 		}
+		stateDb, header = b.runStateAndHeaderHooks(ctx, stateDb, header)
 		return stateDb, header, nil
 	}
 	return nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 }
 
 func (b *EthAPIBackendV2) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+	return b.runGetReceiptsHooks(ctx, hash, receipts), nil
+}
+
+// rawLogs returns the hook-processed logs for the block identified by
+// hash/number, in "raw" form: BlockNumber, BlockHash, TxHash, TxIndex and
+// Index are left zero-valued. It backs both GetLogs, which returns these
+// logs as they are, and GetFilteredLogs, which only derives the fields once
+// it knows at least one log survives the filter.
+func (b *EthAPIBackendV2) rawLogs(ctx context.Context, hash common.Hash, number uint64) [][]*types.Log {
+	logs := rawdb.ReadLogs(b.eth.chainDb, hash, number)
+	return b.runGetLogsHooks(ctx, hash, number, logs)
+}
+
+// GetLogs returns the logs generated by the transactions included in the
+// block identified by hash/number, in raw form: BlockNumber, BlockHash,
+// TxHash, TxIndex and Index are not filled in, so the block body is not
+// loaded. Callers that need these fields call DeriveLogFields on the
+// result. GetFilteredLogs combines both steps and derives the fields only
+// for blocks that contain at least one matching log.
+func (b *EthAPIBackendV2) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	return b.rawLogs(ctx, hash, number), nil
 }
 
-func (b *EthAPIBackendV2) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
-	return rawdb.ReadLogs(b.eth.chainDb, hash, number), nil
+// DeriveLogFields fills in the BlockNumber, BlockHash, TxHash, TxIndex and
+// Index fields of logs previously returned by rawLogs. logs must be grouped
+// by transaction in the same shape rawLogs returns it: one inner slice per
+// transaction of the block, in transaction order.
+func (b *EthAPIBackendV2) DeriveLogFields(ctx context.Context, hash common.Hash, number uint64, logs [][]*types.Log) error {
+	body := b.eth.blockchain.GetBody(hash)
+	if body == nil {
+		return errors.New("block body not found")
+	}
+	if len(body.Transactions) != len(logs) {
+		return fmt.Errorf("transaction and log count mismatch: %d vs %d", len(body.Transactions), len(logs))
+	}
+	var logIndex uint
+	for txIndex, txLogs := range logs {
+		txHash := body.Transactions[txIndex].Hash()
+		for _, l := range txLogs {
+			l.BlockNumber = number
+			l.BlockHash = hash
+			l.TxHash = txHash
+			l.TxIndex = uint(txIndex)
+			l.Index = logIndex
+			logIndex++
+		}
+	}
+	return nil
+}
+
+// GetFilteredLogs is the filter-aware counterpart to GetLogs: it matches
+// addresses and topics against the raw logs of the block first, and only
+// calls DeriveLogFields - and therefore only pays for loading the block
+// body - once it knows at least one log in the block actually survives the
+// filter. An empty addresses or topics entry matches anything, mirroring
+// the semantics of eth_getLogs filter criteria.
+//
+// This is the method a bloom-indexed filter implementation (e.g.
+// eth/filters) should call instead of GetLogs plus its own post-filtering,
+// so that the derivation cost is only paid for blocks that actually
+// contribute a result.
+func (b *EthAPIBackendV2) GetFilteredLogs(ctx context.Context, hash common.Hash, number uint64, addresses []common.Address, topics [][]common.Hash) ([][]*types.Log, error) {
+	rawLogs := b.rawLogs(ctx, hash, number)
+	matched := make([][]*types.Log, len(rawLogs))
+	var anyMatch bool
+	for i, txLogs := range rawLogs {
+		for _, l := range txLogs {
+			if logMatchesFilter(l, addresses, topics) {
+				matched[i] = append(matched[i], l)
+				anyMatch = true
+			}
+		}
+	}
+	if !anyMatch {
+		return nil, nil
+	}
+	// At least one log in the block matched; derive the context fields for
+	// the whole block so the matched entries (which alias the same *Log
+	// values) come back fully populated.
+	if err := b.DeriveLogFields(ctx, hash, number, rawLogs); err != nil {
+		return nil, err
+	}
+	out := matched[:0]
+	for _, txLogs := range matched {
+		if len(txLogs) > 0 {
+			out = append(out, txLogs)
+		}
+	}
+	return out, nil
+}
+
+// logMatchesFilter reports whether l satisfies the eth_getLogs-style
+// address/topic filter criteria: an empty addresses list matches any
+// address, and each topics[i] position either matches any of the hashes
+// listed (an OR) or, if empty, matches anything at that position.
+func logMatchesFilter(l *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var found bool
+		for _, addr := range addresses {
+			if l.Address == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(topics) > len(l.Topics) {
+		return false
+	}
+	for i, want := range topics {
+		if len(want) == 0 {
+			continue
+		}
+		var found bool
+		for _, t := range want {
+			if l.Topics[i] == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 func (b *EthAPIBackendV2) GetEVM(ctx context.Context, state vm.StateDB, header *types.Header, vmConfig *vm.Config, blockCtx *vm.BlockContext) *vm.EVM {
@@ -260,7 +415,32 @@ func (b *EthAPIBackendV2) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEv
 }
 
 func (b *EthAPIBackendV2) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
-	return b.eth.BlockChain().SubscribeChainEvent(ch)
+	if !b.pluginsRegistry().hasAny() {
+		return b.eth.BlockChain().SubscribeChainEvent(ch)
+	}
+	// Interpose a relay channel so every chain event can be fanned out to
+	// the registered ChainEventSink plugins before (and regardless of
+	// whether) it also reaches ch.
+	relay := make(chan core.ChainEvent, cap(ch))
+	sub := b.eth.BlockChain().SubscribeChainEvent(relay)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-relay:
+				b.fanOutChainEvent(ev)
+				select {
+				case ch <- ev:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
 }
 
 func (b *EthAPIBackendV2) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
@@ -268,22 +448,73 @@ func (b *EthAPIBackendV2) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent)
 }
 
 func (b *EthAPIBackendV2) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
-	return b.eth.BlockChain().SubscribeLogsEvent(ch)
+	if !b.pluginsRegistry().hasAny() {
+		return b.eth.BlockChain().SubscribeLogsEvent(ch)
+	}
+	relay := make(chan []*types.Log, cap(ch))
+	sub := b.eth.BlockChain().SubscribeLogsEvent(relay)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case logs := <-relay:
+				b.fanOutLogsEvent(logs)
+				select {
+				case ch <- logs:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
 }
 
+// SendTx submits signedTx to the pool. Blob transactions (EIP-4844) must
+// carry their sidecar (blobs, commitments and proofs); a blob transaction
+// without one is rejected, unless its hash matches a sidecar still held in
+// the blob limbo, in which case the sidecar is reattached automatically so
+// a transaction removed by a reorg can be resubmitted without redownloading
+// it.
 func (b *EthAPIBackendV2) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if err := b.runSendTxHooks(ctx, signedTx); err != nil {
+		return err
+	}
+	if signedTx.Type() == types.BlobTxType {
+		sidecar := signedTx.BlobTxSidecar()
+		if sidecar == nil {
+			cached, ok := b.limbo().get(signedTx.Hash())
+			if !ok {
+				return errors.New("missing blob sidecar for blob transaction")
+			}
+			signedTx = signedTx.WithBlobTxSidecar(cached)
+			sidecar = cached
+		}
+		b.limbo().stash(signedTx.Hash(), sidecar)
+	}
 	if locals := b.eth.localTxTracker; locals != nil {
 		locals.Track(signedTx)
 	}
 	return b.eth.txPool.Add([]*types.Transaction{signedTx}, false)[0]
 }
 
+// GetPoolTransactions returns every pending transaction in the pool. Blob
+// transactions are returned with their sidecar stripped, so that
+// block-building and import code consuming this method cannot accidentally
+// embed sidecar bytes into a block; use GetPoolTransaction or BlobSidecar
+// to retrieve a blob transaction's sidecar.
 func (b *EthAPIBackendV2) GetPoolTransactions() (types.Transactions, error) {
 	pending := b.eth.txPool.Pending(txpool.PendingFilter{})
 	var txs types.Transactions
 	for _, batch := range pending {
 		for _, lazy := range batch {
 			if tx := lazy.Resolve(); tx != nil {
+				if tx.Type() == types.BlobTxType {
+					tx = tx.WithoutBlobTxSidecar()
+				}
 				txs = append(txs, tx)
 			}
 		}
@@ -291,6 +522,8 @@ func (b *EthAPIBackendV2) GetPoolTransactions() (types.Transactions, error) {
 	return txs, nil
 }
 
+// GetPoolTransaction returns the pooled transaction for hash, including its
+// sidecar if it is a blob transaction.
 func (b *EthAPIBackendV2) GetPoolTransaction(hash common.Hash) *types.Transaction {
 	return b.eth.txPool.Get(hash)
 }
@@ -313,6 +546,11 @@ func (b *EthAPIBackendV2) GetTransaction(ctx context.Context, txHash common.Hash
 	if lookup == nil || tx == nil {
 		return false, nil, common.Hash{}, 0, 0, nil
 	}
+	// The transaction is durably included; any sidecar kept in limbo in
+	// case of resubmission is no longer needed.
+	if tx.Type() == types.BlobTxType {
+		b.limbo().forget(txHash)
+	}
 	return true, tx, lookup.BlockHash, lookup.BlockIndex, lookup.Index, nil
 }
 
@@ -415,9 +653,13 @@ func (b *EthAPIBackendV2) CurrentHeader() *types.Header {
 	return b.eth.blockchain.CurrentHeader()
 }
 
+// StateAtBlock returns the state as it existed right after block, pinning
+// the underlying trie/snapshot layer and handing back a release function to
+// unpin it once the caller is done. If the state is not already resident in
+// memory, up to reexec ancestor blocks are re-executed from the nearest
+// available snapshot to rebuild it, honoring preferDisk.
 func (b *EthAPIBackendV2) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base vm.StateDB, readOnly bool, preferDisk bool) (vm.StateDB, tracers.StateReleaseFunc, error) {
-	//return b.eth.stateAtBlock(ctx, block, reexec, base, readOnly, preferDisk)
-	return base, nil, nil
+	return b.eth.stateAtBlock(ctx, block, reexec, base, readOnly, preferDisk)
 }
 
 func (b *EthAPIBackendV2) StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*types.Transaction, vm.BlockContext, vm.StateDB, tracers.StateReleaseFunc, error) {
@@ -0,0 +1,69 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLogMatchesFilterEmptyCriteriaMatchesAnything(t *testing.T) {
+	l := &types.Log{
+		Address: common.HexToAddress("0x1"),
+		Topics:  []common.Hash{common.HexToHash("0xa")},
+	}
+	if !logMatchesFilter(l, nil, nil) {
+		t.Fatal("expected empty addresses/topics to match any log")
+	}
+}
+
+func TestLogMatchesFilterAddress(t *testing.T) {
+	l := &types.Log{Address: common.HexToAddress("0x1")}
+	addresses := []common.Address{common.HexToAddress("0x2"), common.HexToAddress("0x1")}
+	if !logMatchesFilter(l, addresses, nil) {
+		t.Fatal("expected log to match one of the listed addresses")
+	}
+	if logMatchesFilter(l, []common.Address{common.HexToAddress("0x2")}, nil) {
+		t.Fatal("expected log not to match an address list that excludes it")
+	}
+}
+
+func TestLogMatchesFilterTopicsOrWithinPositionAndWildcards(t *testing.T) {
+	l := &types.Log{Topics: []common.Hash{common.HexToHash("0xa"), common.HexToHash("0xb")}}
+
+	// Position 0 must be one of {0xa, 0xc}; position 1 is a wildcard.
+	topics := [][]common.Hash{{common.HexToHash("0xa"), common.HexToHash("0xc")}, {}}
+	if !logMatchesFilter(l, nil, topics) {
+		t.Fatal("expected log to match: position 0 satisfied by OR, position 1 is a wildcard")
+	}
+
+	// Position 0 must be 0xc only: no match.
+	topics = [][]common.Hash{{common.HexToHash("0xc")}}
+	if logMatchesFilter(l, nil, topics) {
+		t.Fatal("expected log not to match when no alternative at position 0 is present")
+	}
+}
+
+func TestLogMatchesFilterTopicsLongerThanLogTopics(t *testing.T) {
+	l := &types.Log{Topics: []common.Hash{common.HexToHash("0xa")}}
+	topics := [][]common.Hash{{common.HexToHash("0xa")}, {common.HexToHash("0xb")}}
+	if logMatchesFilter(l, nil, topics) {
+		t.Fatal("expected no match when the filter specifies more topic positions than the log has")
+	}
+}
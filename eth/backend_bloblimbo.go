@@ -0,0 +1,195 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// errBlobSidecarNotFound is returned by BlobSidecar when txHash is unknown
+// to both the pool and the limbo cache.
+var errBlobSidecarNotFound = errors.New("blob sidecar not found")
+
+// blobLimboCapacity bounds the number of sidecars blobLimbo holds at once,
+// so a flood of short-lived blob transactions cannot grow it unbounded.
+const blobLimboCapacity = 1024
+
+// blobLimboMaxAge is how long a sidecar is kept in limbo before prune drops
+// it, on the assumption that a transaction not reclaimed by then is not
+// coming back.
+const blobLimboMaxAge = time.Hour
+
+// blobLimboPruneInterval is how often the background goroutine started by
+// start calls prune.
+const blobLimboPruneInterval = 10 * time.Minute
+
+// blobLimboEntry is a sidecar held in limbo together with the time it was
+// stashed, so prune can age out entries that are never reclaimed.
+type blobLimboEntry struct {
+	sidecar *types.BlobTxSidecar
+	stashed time.Time
+}
+
+// blobLimbo retains the sidecar of a blob transaction for a while after the
+// transaction itself has left the pool, most commonly because a reorg
+// temporarily removed the block it was included in. Re-submitting the same
+// transaction, minus its sidecar, then does not require the sidecar to be
+// re-downloaded from whichever peer or client originally supplied it.
+type blobLimbo struct {
+	mu      sync.Mutex
+	entries map[common.Hash]blobLimboEntry
+	order   []common.Hash // insertion order, oldest first, for capacity eviction
+
+	startOnce sync.Once
+	quit      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func newBlobLimbo() *blobLimbo {
+	return &blobLimbo{entries: make(map[common.Hash]blobLimboEntry)}
+}
+
+// stash records the sidecar of a blob transaction known to the backend, so
+// it remains recoverable if the transaction is later removed and
+// resubmitted without it.
+func (l *blobLimbo) stash(hash common.Hash, sidecar *types.BlobTxSidecar) {
+	if sidecar == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, exists := l.entries[hash]; !exists {
+		l.order = append(l.order, hash)
+	}
+	l.entries[hash] = blobLimboEntry{sidecar: sidecar, stashed: time.Now()}
+	for len(l.order) > blobLimboCapacity {
+		oldest := l.order[0]
+		l.order = l.order[1:]
+		delete(l.entries, oldest)
+	}
+}
+
+// get returns the sidecar stashed for hash, if any.
+func (l *blobLimbo) get(hash common.Hash) (*types.BlobTxSidecar, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[hash]
+	if !ok {
+		return nil, false
+	}
+	return entry.sidecar, true
+}
+
+// forget drops the sidecar for hash, once the corresponding transaction has
+// been durably included or is no longer of interest.
+func (l *blobLimbo) forget(hash common.Hash) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, hash)
+	for i, h := range l.order {
+		if h == hash {
+			l.order = append(l.order[:i], l.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// prune drops entries older than blobLimboMaxAge.
+func (l *blobLimbo) prune() {
+	cutoff := time.Now().Add(-blobLimboMaxAge)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kept := l.order[:0]
+	for _, hash := range l.order {
+		if entry, ok := l.entries[hash]; ok && entry.stashed.Before(cutoff) {
+			delete(l.entries, hash)
+			continue
+		}
+		kept = append(kept, hash)
+	}
+	l.order = kept
+}
+
+// start begins a background goroutine that periodically prunes stale
+// entries, so a sidecar that is never reclaimed does not sit in memory
+// forever. It is started alongside the plugin lifecycle (see StartPlugins)
+// and is idempotent: calling it more than once has no additional effect.
+func (l *blobLimbo) start() {
+	l.startOnce.Do(func() {
+		l.quit = make(chan struct{})
+		l.wg.Add(1)
+		go l.pruneLoop()
+	})
+}
+
+func (l *blobLimbo) pruneLoop() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(blobLimboPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.prune()
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// stop signals the pruning goroutine started by start to exit and waits for
+// it to do so. It is a no-op if start was never called or stop has already
+// run.
+func (l *blobLimbo) stop() {
+	if l.quit == nil {
+		return
+	}
+	close(l.quit)
+	l.wg.Wait()
+	l.quit = nil
+}
+
+// limbo returns the backend's blob limbo, creating it on first use.
+func (b *EthAPIBackendV2) limbo() *blobLimbo {
+	b.blobLimboOnce.Do(func() {
+		b.blobLimboCache = newBlobLimbo()
+	})
+	return b.blobLimboCache
+}
+
+// BlobSidecar returns the EIP-4844 sidecar (blobs, commitments and proofs)
+// belonging to txHash, for RPC consumers such as an eth_getBlobSidecarByTxHash
+// style endpoint. It first checks the pool, where the blob subpool keeps
+// sidecars attached to their pending transaction, then falls back to the
+// limbo cache that retains sidecars of transactions temporarily removed by
+// a reorg.
+func (b *EthAPIBackendV2) BlobSidecar(ctx context.Context, txHash common.Hash) (*types.BlobTxSidecar, error) {
+	if tx := b.eth.txPool.Get(txHash); tx != nil {
+		if sidecar := tx.BlobTxSidecar(); sidecar != nil {
+			return sidecar, nil
+		}
+	}
+	if sidecar, ok := b.limbo().get(txHash); ok {
+		return sidecar, nil
+	}
+	return nil, errBlobSidecarNotFound
+}
@@ -0,0 +1,111 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestBlobLimboStashGetForget(t *testing.T) {
+	l := newBlobLimbo()
+	hash := common.HexToHash("0x1")
+	sidecar := &types.BlobTxSidecar{}
+
+	if _, ok := l.get(hash); ok {
+		t.Fatal("expected no sidecar before stash")
+	}
+	l.stash(hash, sidecar)
+	got, ok := l.get(hash)
+	if !ok || got != sidecar {
+		t.Fatal("expected stashed sidecar to be returned")
+	}
+	l.forget(hash)
+	if _, ok := l.get(hash); ok {
+		t.Fatal("expected sidecar to be gone after forget")
+	}
+}
+
+func TestBlobLimboStashIgnoresNilSidecar(t *testing.T) {
+	l := newBlobLimbo()
+	hash := common.HexToHash("0x1")
+	l.stash(hash, nil)
+	if _, ok := l.get(hash); ok {
+		t.Fatal("expected stash(nil) to be a no-op")
+	}
+}
+
+func TestBlobLimboCapacityEviction(t *testing.T) {
+	l := newBlobLimbo()
+	for i := 0; i < blobLimboCapacity+10; i++ {
+		hash := common.BigToHash(big.NewInt(int64(i)))
+		l.stash(hash, &types.BlobTxSidecar{})
+	}
+	l.mu.Lock()
+	n := len(l.entries)
+	l.mu.Unlock()
+	if n != blobLimboCapacity {
+		t.Fatalf("limbo holds %d entries, want capacity %d", n, blobLimboCapacity)
+	}
+	// The oldest entries should have been evicted first.
+	if _, ok := l.get(common.BigToHash(big.NewInt(0))); ok {
+		t.Fatal("expected the oldest entry to have been evicted")
+	}
+	if _, ok := l.get(common.BigToHash(big.NewInt(int64(blobLimboCapacity + 9)))); !ok {
+		t.Fatal("expected the most recent entry to still be present")
+	}
+}
+
+func TestBlobLimboPruneDropsStaleEntries(t *testing.T) {
+	l := newBlobLimbo()
+	hash := common.HexToHash("0x1")
+	l.stash(hash, &types.BlobTxSidecar{})
+	// Backdate the entry past blobLimboMaxAge without waiting for real time
+	// to pass.
+	l.mu.Lock()
+	entry := l.entries[hash]
+	entry.stashed = time.Now().Add(-blobLimboMaxAge - time.Second)
+	l.entries[hash] = entry
+	l.mu.Unlock()
+
+	l.prune()
+	if _, ok := l.get(hash); ok {
+		t.Fatal("expected prune to drop an entry older than blobLimboMaxAge")
+	}
+}
+
+func TestBlobLimboPruneKeepsFreshEntries(t *testing.T) {
+	l := newBlobLimbo()
+	hash := common.HexToHash("0x1")
+	l.stash(hash, &types.BlobTxSidecar{})
+	l.prune()
+	if _, ok := l.get(hash); !ok {
+		t.Fatal("expected prune to keep a freshly stashed entry")
+	}
+}
+
+func TestBlobLimboStartStopIsIdempotentAndClean(t *testing.T) {
+	l := newBlobLimbo()
+	l.start()
+	l.start() // must not panic or start a second goroutine
+	l.stop()
+	l.stop() // must not panic or block
+}
@@ -0,0 +1,155 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// httpHandlerEntry wraps a registered handler with an enable flag, so
+// operators can turn a surface on or off at runtime without unregistering
+// it from the HTTP server's mux.
+type httpHandlerEntry struct {
+	path    string
+	handler http.Handler
+	enabled bool
+}
+
+func (e *httpHandlerEntry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !e.enabled {
+		http.NotFound(w, r)
+		return
+	}
+	e.handler.ServeHTTP(w, r)
+}
+
+// httpHandlerRegistry tracks the handlers installed through
+// RegisterHTTPHandler, by name, so duplicate registrations and
+// enable/disable toggling have a single source of truth.
+type httpHandlerRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*httpHandlerEntry
+}
+
+func newHTTPHandlerRegistry() *httpHandlerRegistry {
+	return &httpHandlerRegistry{entries: make(map[string]*httpHandlerEntry)}
+}
+
+// HTTPHandlerSpec describes a single HTTP surface a plugin wants exposed on
+// the node's user-facing HTTP server. See HTTPHandlerPlugin.
+type HTTPHandlerSpec struct {
+	Name    string
+	Path    string
+	Handler http.Handler
+}
+
+// HTTPHandlerPlugin is implemented by plugins that expose non-JSON-RPC HTTP
+// surfaces (a Prometheus scrape endpoint, a REST-style receipts endpoint,
+// ...) alongside the hooks they intercept. HTTPHandlers is called once, from
+// RegisterBackendPlugin, while the node is still in its initializing phase;
+// unlike Start, which may run after the HTTP server is already serving
+// requests, this guarantees node.Node.RegisterHandler is called at a point
+// where it is still accepted.
+type HTTPHandlerPlugin interface {
+	BackendPlugin
+	HTTPHandlers() []HTTPHandlerSpec
+}
+
+// httpHandlersRegistry returns the backend's HTTP handler registry, creating
+// it on first use. It never returns nil, and is the only code path allowed
+// to read or write b.httpHandlers: the field itself must not be accessed
+// directly, since doing so races with this lazy initialization.
+func (b *EthAPIBackendV2) httpHandlersRegistry() *httpHandlerRegistry {
+	b.httpHandlersOnce.Do(func() {
+		b.httpHandlers = newHTTPHandlerRegistry()
+	})
+	return b.httpHandlers
+}
+
+// AttachStack records the node stack whose user-facing HTTP server
+// RegisterHTTPHandler installs handlers onto. RegisterPlugins calls this
+// automatically before registering any plugin; callers that register
+// plugins directly through RegisterBackendPlugin instead of RegisterPlugins
+// must call AttachStack themselves first, or RegisterHTTPHandler (and any
+// HTTPHandlerPlugin wired through RegisterBackendPlugin) will fail with
+// "node stack not attached to backend".
+func (b *EthAPIBackendV2) AttachStack(stack *node.Node) {
+	b.stack = stack
+}
+
+// RegisterHTTPHandler exposes handler under path on the node's user-facing
+// HTTP server, alongside the JSON-RPC endpoint. This lets built-in
+// subsystems (GraphQL, a Prometheus scrape endpoint backed by Stats,
+// SyncProgress and BloomStatus, a REST-style receipts endpoint, ...) and
+// third-party plugins serve non-JSON-RPC traffic without spinning up a
+// second listener. Because the handler is installed on the same node.Node
+// HTTP server as the JSON-RPC endpoint, it is subject to the same
+// authentication and CORS configuration.
+//
+// Registering two handlers under the same name returns an error. A handler
+// starts out enabled; use EnableHTTPHandler to toggle it without
+// unregistering it.
+//
+// node.Node.RegisterHandler only accepts new handlers while the node is
+// still initializing and panics once it has started; callers driven by
+// plugin lifecycle should register through HTTPHandlerPlugin instead of
+// calling this from a plugin's Start method.
+func (b *EthAPIBackendV2) RegisterHTTPHandler(name, path string, handler http.Handler) error {
+	if b.stack == nil {
+		return errors.New("node stack not attached to backend")
+	}
+	handlers := b.httpHandlersRegistry()
+	handlers.mu.Lock()
+	if _, exists := handlers.entries[name]; exists {
+		handlers.mu.Unlock()
+		return fmt.Errorf("http handler %q already registered", name)
+	}
+	entry := &httpHandlerEntry{path: path, handler: handler, enabled: true}
+	handlers.entries[name] = entry
+	handlers.mu.Unlock()
+
+	b.stack.RegisterHandler(name, path, entry)
+	log.Info("Registered custom HTTP handler", "name", name, "path", path)
+	return nil
+}
+
+// EnableHTTPHandler toggles the handler previously registered under name on
+// or off. A disabled handler responds 404 to every request without being
+// removed from the HTTP server's mux.
+func (b *EthAPIBackendV2) EnableHTTPHandler(name string, enabled bool) error {
+	handlers := b.httpHandlersRegistry()
+	handlers.mu.Lock()
+	defer handlers.mu.Unlock()
+	entry, ok := handlers.entries[name]
+	if !ok {
+		return fmt.Errorf("http handler %q not registered", name)
+	}
+	entry.enabled = enabled
+	return nil
+}
+
+// RegisterHTTPHandler registers handler on s's backend, see
+// EthAPIBackendV2.RegisterHTTPHandler.
+func (s *Ethereum) RegisterHTTPHandler(name, path string, handler http.Handler) error {
+	return s.APIBackend.RegisterHTTPHandler(name, path, handler)
+}
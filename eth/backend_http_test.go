@@ -0,0 +1,57 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubHandler struct{ calls int }
+
+func (h *stubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { h.calls++ }
+
+func TestHTTPHandlerEntryServeHTTPRespectsEnabled(t *testing.T) {
+	inner := &stubHandler{}
+	entry := &httpHandlerEntry{path: "/x", handler: inner, enabled: false}
+
+	entry.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	if inner.calls != 0 {
+		t.Fatal("expected a disabled entry not to forward the request")
+	}
+
+	entry.enabled = true
+	entry.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	if inner.calls != 1 {
+		t.Fatal("expected an enabled entry to forward the request")
+	}
+}
+
+func TestRegisterHTTPHandlerRequiresStack(t *testing.T) {
+	b := &EthAPIBackendV2{}
+	if err := b.RegisterHTTPHandler("x", "/x", &stubHandler{}); err == nil {
+		t.Fatal("expected an error when no node stack is attached")
+	}
+}
+
+func TestEnableHTTPHandlerUnknownName(t *testing.T) {
+	b := &EthAPIBackendV2{}
+	if err := b.EnableHTTPHandler("missing", true); err == nil {
+		t.Fatal("expected an error toggling a handler that was never registered")
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// BackendPlugin is the base interface implemented by every plugin that
+// registers with EthAPIBackendV2. A plugin only needs to additionally
+// implement the hook interfaces below (GetLogsHook, SendTxHook, ...) that
+// correspond to the calls it wants to intercept or augment; unimplemented
+// hooks are simply skipped when the chain is run. This mirrors the
+// plugeth-style extension model where plugins are loaded as regular Go
+// modules rather than forking the node.
+type BackendPlugin interface {
+	// Name returns a short, unique, human readable identifier for the
+	// plugin. It is used for conflict detection in RegisterBackendPlugin,
+	// as a metrics label and in log output.
+	Name() string
+}
+
+// PluginLifecycle is implemented by plugins that run background work (a
+// subscription fan-out goroutine, a remote sink connection, ...) alongside
+// the node. Start is called once all plugins have been registered and Stop
+// is called on node shutdown.
+type PluginLifecycle interface {
+	Start() error
+	Stop() error
+}
+
+// GetLogsHook lets a plugin rewrite or augment the logs returned by
+// GetLogs, e.g. to inject synthetic logs that were not produced by block
+// execution. logs are in the same raw, not-yet-field-derived form GetLogs
+// itself returns them in; see DeriveLogFields.
+type GetLogsHook interface {
+	BackendPlugin
+	OnGetLogs(ctx context.Context, hash common.Hash, number uint64, logs [][]*types.Log) ([][]*types.Log, error)
+}
+
+// GetReceiptsHook lets a plugin rewrite or augment receipts returned by
+// GetReceipts.
+type GetReceiptsHook interface {
+	BackendPlugin
+	OnGetReceipts(ctx context.Context, hash common.Hash, receipts types.Receipts) (types.Receipts, error)
+}
+
+// SendTxHook lets a plugin observe, veto or otherwise react to a
+// transaction before it is submitted to the pool. Returning a non-nil
+// error aborts submission and is propagated back to the RPC caller.
+type SendTxHook interface {
+	BackendPlugin
+	OnSendTx(ctx context.Context, tx *types.Transaction) error
+}
+
+// StateAndHeaderHook lets a plugin observe or substitute the state/header
+// pair returned by StateAndHeaderByNumber and StateAndHeaderByNumberOrHash.
+type StateAndHeaderHook interface {
+	BackendPlugin
+	OnStateAndHeader(ctx context.Context, state vm.StateDB, header *types.Header) (vm.StateDB, *types.Header, error)
+}
+
+// ChainEventSink receives a copy of every chain event fanned out through
+// SubscribeChainEvent, in addition to whatever the plugin subscribes to on
+// its own.
+type ChainEventSink interface {
+	BackendPlugin
+	OnChainEvent(core.ChainEvent)
+}
+
+// LogsEventSink receives a copy of every logs batch fanned out through
+// SubscribeLogsEvent.
+type LogsEventSink interface {
+	BackendPlugin
+	OnLogsEvent([]*types.Log)
+}
+
+// pluginRegistry tracks the plugins registered on a EthAPIBackendV2 and runs
+// the hook chains on its behalf. Hooks are run in registration order; a
+// panic in one plugin is recovered and logged so it cannot take down the
+// calling goroutine or poison the chain for the plugins registered after it.
+type pluginRegistry struct {
+	mu      sync.RWMutex
+	plugins []BackendPlugin
+	byName  map[string]BackendPlugin
+}
+
+func newPluginRegistry() *pluginRegistry {
+	return &pluginRegistry{byName: make(map[string]BackendPlugin)}
+}
+
+func (r *pluginRegistry) register(name string, p BackendPlugin) error {
+	if p.Name() != name {
+		return fmt.Errorf("backend plugin name mismatch: registered as %q but reports %q", name, p.Name())
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.byName[name]; exists {
+		return fmt.Errorf("backend plugin %q already registered", name)
+	}
+	r.byName[name] = p
+	r.plugins = append(r.plugins, p)
+	return nil
+}
+
+func (r *pluginRegistry) list() []BackendPlugin {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]BackendPlugin, len(r.plugins))
+	copy(out, r.plugins)
+	return out
+}
+
+// hasAny reports whether any plugin is registered, so hot paths like
+// SubscribeChainEvent can skip interposing a relay when there is nothing to
+// fan events out to.
+func (r *pluginRegistry) hasAny() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.plugins) > 0
+}
+
+// start starts every registered plugin that implements PluginLifecycle, in
+// registration order, isolating panics and collecting the first error
+// encountered so that a single misbehaving plugin does not block the rest
+// from starting.
+func (r *pluginRegistry) start() (err error) {
+	for _, p := range r.list() {
+		lc, ok := p.(PluginLifecycle)
+		if !ok {
+			continue
+		}
+		if startErr := runProtected(p.Name(), "Start", lc.Start); startErr != nil && err == nil {
+			err = startErr
+		}
+	}
+	return err
+}
+
+// stop stops every registered plugin that implements PluginLifecycle, in
+// reverse registration order, isolating panics the same way start does.
+func (r *pluginRegistry) stop() (err error) {
+	plugins := r.list()
+	for i := len(plugins) - 1; i >= 0; i-- {
+		lc, ok := plugins[i].(PluginLifecycle)
+		if !ok {
+			continue
+		}
+		if stopErr := runProtected(plugins[i].Name(), "Stop", lc.Stop); stopErr != nil && err == nil {
+			err = stopErr
+		}
+	}
+	return err
+}
+
+// runProtected invokes fn, converting any panic into an error and recording
+// per-plugin, per-hook metrics so a single misbehaving plugin is visible
+// without needing to reproduce the panic locally.
+func runProtected(plugin, hook string, fn func() error) (err error) {
+	pluginHookCalls(plugin, hook).Inc(1)
+	defer func() {
+		if r := recover(); r != nil {
+			pluginHookPanics(plugin, hook).Inc(1)
+			log.Error("Backend plugin hook panicked", "plugin", plugin, "hook", hook, "err", r)
+			err = fmt.Errorf("plugin %q hook %q panicked: %v", plugin, hook, r)
+		}
+		if err != nil {
+			pluginHookErrors(plugin, hook).Inc(1)
+		}
+	}()
+	return fn()
+}
+
+func pluginHookCalls(plugin, hook string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(fmt.Sprintf("eth/plugin/%s/%s/calls", plugin, hook), nil)
+}
+
+func pluginHookErrors(plugin, hook string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(fmt.Sprintf("eth/plugin/%s/%s/errors", plugin, hook), nil)
+}
+
+func pluginHookPanics(plugin, hook string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(fmt.Sprintf("eth/plugin/%s/%s/panics", plugin, hook), nil)
+}
+
+// pluginsRegistry returns the backend's plugin registry, creating it on
+// first use. It never returns nil, and is the only code path allowed to
+// read or write b.plugins: the field itself must not be accessed directly,
+// since doing so races with this lazy initialization.
+func (b *EthAPIBackendV2) pluginsRegistry() *pluginRegistry {
+	b.pluginsOnce.Do(func() {
+		b.plugins = newPluginRegistry()
+	})
+	return b.plugins
+}
+
+// RegisterBackendPlugin registers p under name so that it participates in
+// the hook chains run by EthAPIBackendV2 (GetLogs, GetReceipts, SendTx,
+// StateAndHeaderByNumber, SubscribeChainEvent and SubscribeLogsEvent).
+// Plugins are chained in registration order and only need to implement the
+// hook interfaces they care about. Registering two plugins under the same
+// name returns an error.
+//
+// If p implements HTTPHandlerPlugin, its HTTP handlers are registered here,
+// synchronously, rather than from Start: node.Node.RegisterHandler only
+// accepts new handlers while the node is still initializing, and by the
+// time a plugin's Start runs the HTTP server may already be serving
+// requests. RegisterBackendPlugin is expected to be called during node
+// setup, before the stack starts, so this is the last point at which
+// registering a handler is guaranteed to be safe.
+//
+// If the backend has already been started (see StartPlugins), the plugin's
+// Start method, if any, is invoked immediately.
+func (b *EthAPIBackendV2) RegisterBackendPlugin(name string, p BackendPlugin) error {
+	if err := b.pluginsRegistry().register(name, p); err != nil {
+		return err
+	}
+	if hp, ok := p.(HTTPHandlerPlugin); ok {
+		for _, spec := range hp.HTTPHandlers() {
+			if err := b.RegisterHTTPHandler(spec.Name, spec.Path, spec.Handler); err != nil {
+				return fmt.Errorf("backend plugin %q: %w", name, err)
+			}
+		}
+	}
+	if b.pluginsStarted {
+		if lc, ok := p.(PluginLifecycle); ok {
+			return runProtected(name, "Start", lc.Start)
+		}
+	}
+	return nil
+}
+
+// StartPlugins starts every plugin registered so far, and begins periodic
+// pruning of the blob sidecar limbo (see backend_bloblimbo.go) so its
+// promised time-based eviction actually runs. It is called once during node
+// startup, after the configured set of plugins has been loaded.
+func (b *EthAPIBackendV2) StartPlugins() error {
+	b.pluginsStarted = true
+	b.limbo().start()
+	return b.pluginsRegistry().start()
+}
+
+// StopPlugins stops every registered plugin and the blob limbo pruner. It is
+// called once during node shutdown.
+func (b *EthAPIBackendV2) StopPlugins() error {
+	b.limbo().stop()
+	return b.pluginsRegistry().stop()
+}
+
+// runGetLogsHooks runs the GetLogsHook chain over logs, in registration
+// order, letting each plugin see the output of the previous one.
+func (b *EthAPIBackendV2) runGetLogsHooks(ctx context.Context, hash common.Hash, number uint64, logs [][]*types.Log) [][]*types.Log {
+	for _, p := range b.pluginsRegistry().list() {
+		hook, ok := p.(GetLogsHook)
+		if !ok {
+			continue
+		}
+		name := p.Name()
+		var out [][]*types.Log
+		err := runProtected(name, "OnGetLogs", func() (err error) {
+			out, err = hook.OnGetLogs(ctx, hash, number, logs)
+			return err
+		})
+		if err != nil {
+			log.Error("Backend plugin OnGetLogs failed, ignoring", "plugin", name, "err", err)
+			continue
+		}
+		logs = out
+	}
+	return logs
+}
+
+// runGetReceiptsHooks runs the GetReceiptsHook chain over receipts.
+func (b *EthAPIBackendV2) runGetReceiptsHooks(ctx context.Context, hash common.Hash, receipts types.Receipts) types.Receipts {
+	for _, p := range b.pluginsRegistry().list() {
+		hook, ok := p.(GetReceiptsHook)
+		if !ok {
+			continue
+		}
+		name := p.Name()
+		var out types.Receipts
+		err := runProtected(name, "OnGetReceipts", func() (err error) {
+			out, err = hook.OnGetReceipts(ctx, hash, receipts)
+			return err
+		})
+		if err != nil {
+			log.Error("Backend plugin OnGetReceipts failed, ignoring", "plugin", name, "err", err)
+			continue
+		}
+		receipts = out
+	}
+	return receipts
+}
+
+// runSendTxHooks runs the SendTxHook chain, stopping and returning the error
+// of the first plugin that vetoes the transaction.
+func (b *EthAPIBackendV2) runSendTxHooks(ctx context.Context, tx *types.Transaction) error {
+	for _, p := range b.pluginsRegistry().list() {
+		hook, ok := p.(SendTxHook)
+		if !ok {
+			continue
+		}
+		name := p.Name()
+		if err := runProtected(name, "OnSendTx", func() error { return hook.OnSendTx(ctx, tx) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStateAndHeaderHooks runs the StateAndHeaderHook chain over a
+// state/header pair, letting each plugin see the output of the previous one.
+func (b *EthAPIBackendV2) runStateAndHeaderHooks(ctx context.Context, state vm.StateDB, header *types.Header) (vm.StateDB, *types.Header) {
+	for _, p := range b.pluginsRegistry().list() {
+		hook, ok := p.(StateAndHeaderHook)
+		if !ok {
+			continue
+		}
+		name := p.Name()
+		var newState vm.StateDB
+		var newHeader *types.Header
+		err := runProtected(name, "OnStateAndHeader", func() (err error) {
+			newState, newHeader, err = hook.OnStateAndHeader(ctx, state, header)
+			return err
+		})
+		if err != nil {
+			log.Error("Backend plugin OnStateAndHeader failed, ignoring", "plugin", name, "err", err)
+			continue
+		}
+		state, header = newState, newHeader
+	}
+	return state, header
+}
+
+// fanOutChainEvent delivers ev to every registered ChainEventSink plugin,
+// isolating panics the same way the other hook chains do.
+func (b *EthAPIBackendV2) fanOutChainEvent(ev core.ChainEvent) {
+	for _, p := range b.pluginsRegistry().list() {
+		sink, ok := p.(ChainEventSink)
+		if !ok {
+			continue
+		}
+		name := p.Name()
+		_ = runProtected(name, "OnChainEvent", func() error {
+			sink.OnChainEvent(ev)
+			return nil
+		})
+	}
+}
+
+// fanOutLogsEvent delivers logs to every registered LogsEventSink plugin.
+func (b *EthAPIBackendV2) fanOutLogsEvent(logs []*types.Log) {
+	for _, p := range b.pluginsRegistry().list() {
+		sink, ok := p.(LogsEventSink)
+		if !ok {
+			continue
+		}
+		name := p.Name()
+		_ = runProtected(name, "OnLogsEvent", func() error {
+			sink.OnLogsEvent(logs)
+			return nil
+		})
+	}
+}
+
+// RegisterBackendPlugin registers p on the backend of s, see
+// EthAPIBackendV2.RegisterBackendPlugin.
+func (s *Ethereum) RegisterBackendPlugin(name string, p BackendPlugin) error {
+	return s.APIBackend.RegisterBackendPlugin(name, p)
+}
+
+// pluginLifecycleAdapter adapts a *pluginRegistry to the node.Lifecycle
+// interface so it can be driven by the stack's ordinary start/stop sequence
+// instead of requiring bespoke wiring in cmd/geth.
+type pluginLifecycleAdapter struct {
+	backend *EthAPIBackendV2
+}
+
+func (a *pluginLifecycleAdapter) Start() error {
+	return a.backend.StartPlugins()
+}
+
+func (a *pluginLifecycleAdapter) Stop() error {
+	return a.backend.StopPlugins()
+}
+
+// RegisterPlugins registers every plugin in plugins on s's backend and
+// wires their combined lifecycle into stack, so operators can load plugins
+// from node setup code (e.g. cmd/geth or a custom main package) without
+// forking the tree:
+//
+//	eth.RegisterPlugins(stack, ethService, myplugin.New(), otherplugin.New())
+//
+// It attaches stack to s's backend before registering any plugin, so a
+// plugin implementing HTTPHandlerPlugin can rely on RegisterHTTPHandler
+// working from RegisterBackendPlugin without the caller separately invoking
+// AttachStack first.
+func RegisterPlugins(stack *node.Node, s *Ethereum, plugins ...BackendPlugin) error {
+	s.APIBackend.AttachStack(stack)
+	for _, p := range plugins {
+		if err := s.RegisterBackendPlugin(p.Name(), p); err != nil {
+			return err
+		}
+	}
+	stack.RegisterLifecycle(&pluginLifecycleAdapter{backend: s.APIBackend})
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakePlugin is a minimal BackendPlugin used to exercise pluginRegistry.
+type fakePlugin struct {
+	name        string
+	startCalls  int
+	stopCalls   int
+	startErr    error
+	stopErr     error
+	panicOnCall bool
+}
+
+func (p *fakePlugin) Name() string { return p.name }
+
+// httpPlugin is a fakePlugin that also implements HTTPHandlerPlugin, used to
+// exercise RegisterBackendPlugin's HTTP handler wiring.
+type httpPlugin struct {
+	fakePlugin
+	specs []HTTPHandlerSpec
+}
+
+func (p *httpPlugin) HTTPHandlers() []HTTPHandlerSpec { return p.specs }
+
+func TestRegisterBackendPluginHTTPHandlerRequiresAttachedStack(t *testing.T) {
+	b := &EthAPIBackendV2{}
+	p := &httpPlugin{
+		fakePlugin: fakePlugin{name: "http"},
+		specs:      []HTTPHandlerSpec{{Name: "http", Path: "/x", Handler: &stubHandler{}}},
+	}
+	if err := b.RegisterBackendPlugin(p.name, p); err == nil {
+		t.Fatal("expected registering an HTTPHandlerPlugin before AttachStack to fail")
+	}
+}
+
+func (p *fakePlugin) Start() error {
+	p.startCalls++
+	if p.panicOnCall {
+		panic("boom")
+	}
+	return p.startErr
+}
+
+func (p *fakePlugin) Stop() error {
+	p.stopCalls++
+	return p.stopErr
+}
+
+func TestPluginRegistryRegisterConflict(t *testing.T) {
+	r := newPluginRegistry()
+	if err := r.register("a", &fakePlugin{name: "a"}); err != nil {
+		t.Fatalf("first registration failed: %v", err)
+	}
+	if err := r.register("a", &fakePlugin{name: "a"}); err == nil {
+		t.Fatal("expected error registering duplicate plugin name")
+	}
+	if !r.hasAny() {
+		t.Fatal("expected hasAny to report the registered plugin")
+	}
+}
+
+func TestPluginRegistryRegisterNameMismatch(t *testing.T) {
+	r := newPluginRegistry()
+	if err := r.register("a", &fakePlugin{name: "b"}); err == nil {
+		t.Fatal("expected error when plugin.Name() disagrees with the registered name")
+	}
+}
+
+func TestPluginRegistryOrderedChaining(t *testing.T) {
+	r := newPluginRegistry()
+	var order []string
+	for _, name := range []string{"first", "second", "third"} {
+		name := name
+		if err := r.register(name, &fakePlugin{name: name}); err != nil {
+			t.Fatalf("register %q: %v", name, err)
+		}
+		order = append(order, name)
+	}
+	var seen []string
+	for _, p := range r.list() {
+		seen = append(seen, p.Name())
+	}
+	if len(seen) != len(order) {
+		t.Fatalf("got %d plugins, want %d", len(seen), len(order))
+	}
+	for i := range order {
+		if seen[i] != order[i] {
+			t.Fatalf("plugin order = %v, want %v", seen, order)
+		}
+	}
+}
+
+func TestPluginRegistryStartStopLifecycle(t *testing.T) {
+	r := newPluginRegistry()
+	a := &fakePlugin{name: "a"}
+	b := &fakePlugin{name: "b", startErr: errors.New("b failed to start")}
+	c := &fakePlugin{name: "c"}
+	for _, p := range []*fakePlugin{a, b, c} {
+		if err := r.register(p.name, p); err != nil {
+			t.Fatalf("register %q: %v", p.name, err)
+		}
+	}
+	if err := r.start(); err == nil {
+		t.Fatal("expected start to return the error from plugin b")
+	}
+	for _, p := range []*fakePlugin{a, b, c} {
+		if p.startCalls != 1 {
+			t.Errorf("plugin %q Start called %d times, want 1 (a single failure must not block the rest)", p.name, p.startCalls)
+		}
+	}
+	if err := r.stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	for _, p := range []*fakePlugin{a, b, c} {
+		if p.stopCalls != 1 {
+			t.Errorf("plugin %q Stop called %d times, want 1", p.name, p.stopCalls)
+		}
+	}
+}
+
+func TestPluginRegistryPanicIsolation(t *testing.T) {
+	r := newPluginRegistry()
+	bad := &fakePlugin{name: "bad", panicOnCall: true}
+	good := &fakePlugin{name: "good"}
+	if err := r.register(bad.name, bad); err != nil {
+		t.Fatalf("register bad: %v", err)
+	}
+	if err := r.register(good.name, good); err != nil {
+		t.Fatalf("register good: %v", err)
+	}
+	if err := r.start(); err == nil {
+		t.Fatal("expected start to surface the panicking plugin's error")
+	}
+	if good.startCalls != 1 {
+		t.Fatalf("plugin after the panicking one was not started: startCalls=%d", good.startCalls)
+	}
+}